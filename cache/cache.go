@@ -0,0 +1,124 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cache implements a shared bare-repository mirror cache, keyed by
+// clone URL, so that many working copies ultimately pointing at the same
+// upstream (typically a network of forks) fetch the bulk of their objects
+// only once.
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DevMine/crawld/repo"
+)
+
+// Cache is a shared bare mirror cache rooted at a directory on disk.
+type Cache struct {
+	dir            string
+	minFetchPeriod time.Duration
+}
+
+// New returns a Cache rooted at dir, creating it if necessary.
+// minFetchPeriod is the minimum time to wait between two fetches of the
+// same cache entry.
+func New(dir string, minFetchPeriod time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return nil, err
+	}
+
+	return &Cache{dir: dir, minFetchPeriod: minFetchPeriod}, nil
+}
+
+// key is the sha1 hex digest used to name the cache entry for cloneURL.
+func key(cloneURL string) string {
+	sum := sha1.Sum([]byte(cloneURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// Dir returns the path of the bare mirror for cloneURL.
+func (c *Cache) Dir(cloneURL string) string {
+	return filepath.Join(c.dir, key(cloneURL)+".git")
+}
+
+func (c *Cache) tsFile(cloneURL string) string {
+	return filepath.Join(c.dir, "x-gitcache-ts", key(cloneURL))
+}
+
+// EnsureFetched makes sure the bare mirror for cloneURL holds objects no
+// older than c.minFetchPeriod, fetching from the remote under a dedicated
+// namespace for repoID if needed, and returns the mirror's path. Concurrent
+// callers for the same URL are serialized through a file lock, so that two
+// workers handling forks of the same upstream never fetch into the same
+// bare repo at the same time.
+func (c *Cache) EnsureFetched(repoID uint64, cloneURL string) (string, error) {
+	dir := c.Dir(cloneURL)
+
+	lock, err := repo.NewLock(dir + ".lock")
+	if err != nil {
+		return "", err
+	}
+	defer lock.Close()
+
+	if err := lock.Lock(); err != nil {
+		return "", err
+	}
+
+	if c.fetchedRecently(cloneURL) {
+		return dir, nil
+	}
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := exec.Command("git", "init", "--bare", dir).Run(); err != nil {
+			return "", fmt.Errorf("cache: cannot initialize bare mirror for %s: %s", cloneURL, err)
+		}
+	}
+
+	refspec := fmt.Sprintf("*:refs/namespaces/%d/*", repoID)
+	cmd := exec.Command("git", "--git-dir", dir, "fetch",
+		"--no-write-fetch-head", "--no-recurse-submodules", "--prune", "--force",
+		"--", cloneURL, refspec)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cache: cannot fetch %s into %s (%s): %s",
+			cloneURL, dir, err, strings.TrimSpace(string(out)))
+	}
+
+	if err := c.touch(cloneURL); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func (c *Cache) fetchedRecently(cloneURL string) bool {
+	bs, err := ioutil.ReadFile(c.tsFile(cloneURL))
+	if err != nil {
+		return false
+	}
+
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(bs)), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Since(time.Unix(sec, 0)) < c.minFetchPeriod
+}
+
+func (c *Cache) touch(cloneURL string) error {
+	tsFile := c.tsFile(cloneURL)
+	if err := os.MkdirAll(filepath.Dir(tsFile), 0770); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(tsFile, []byte(strconv.FormatInt(time.Now().Unix(), 10)), 0644)
+}
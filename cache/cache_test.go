@@ -0,0 +1,85 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	k1 := key("https://github.com/foo/bar.git")
+	k2 := key("https://github.com/foo/bar.git")
+	if k1 != k2 {
+		t.Errorf("key is not deterministic: %q != %q", k1, k2)
+	}
+
+	if len(k1) != 40 {
+		t.Errorf("key length = %d, want 40 (a sha1 hex digest)", len(k1))
+	}
+
+	k3 := key("https://github.com/foo/baz.git")
+	if k1 == k3 {
+		t.Errorf("key collided for two different clone URLs: %q", k1)
+	}
+}
+
+func TestCacheFetchedRecently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawld-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := New(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const url = "https://github.com/foo/bar.git"
+
+	if c.fetchedRecently(url) {
+		t.Error("fetchedRecently = true before any fetch was recorded")
+	}
+
+	if err := c.touch(url); err != nil {
+		t.Fatal(err)
+	}
+	if !c.fetchedRecently(url) {
+		t.Error("fetchedRecently = false right after touch, within minFetchPeriod")
+	}
+
+	// simulate a timestamp older than minFetchPeriod.
+	stale := time.Now().Add(-2 * time.Hour)
+	if err := ioutil.WriteFile(c.tsFile(url), []byte(strconv.FormatInt(stale.Unix(), 10)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c.fetchedRecently(url) {
+		t.Error("fetchedRecently = true for a timestamp older than minFetchPeriod")
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawld-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := New(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const url = "https://github.com/foo/bar.git"
+	want := filepath.Join(dir, key(url)+".git")
+	if got := c.Dir(url); got != want {
+		t.Errorf("Dir(%q) = %q, want %q", url, got, want)
+	}
+}
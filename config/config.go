@@ -0,0 +1,147 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package config reads and validates the crawld configuration file.
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/DevMine/crawld/crawlers"
+)
+
+// DatabaseConfig holds the information required to connect to the
+// PostgreSQL database shared with the rest of the DevMine toolchain.
+type DatabaseConfig struct {
+	UserName string `json:"username"`
+	Password string `json:"password"`
+	HostName string `json:"hostname"`
+	Port     int    `json:"port"`
+	DBName   string `json:"dbname"`
+	SSLMode  string `json:"sslmode"`
+}
+
+// Config is the top level crawld configuration.
+type Config struct {
+	Database DatabaseConfig `json:"database"`
+
+	Crawlers []crawlers.Config `json:"crawlers"`
+
+	// CrawlingTimeInterval is the duration to wait between two crawling
+	// passes, in time.ParseDuration format.
+	CrawlingTimeInterval string `json:"crawling_time_interval"`
+
+	// FetchTimeInterval is the duration to wait between two repository
+	// fetching passes, in time.ParseDuration format.
+	FetchTimeInterval string `json:"fetch_time_interval"`
+
+	// FetchLanguages restricts repository fetching to these primary
+	// languages. An empty or nil slice fetches every language.
+	FetchLanguages []string `json:"fetch_languages"`
+
+	// CloneDir is the directory under which every repository is cloned.
+	CloneDir string `json:"clone_dir"`
+
+	// MaxFetcherWorkers is the number of goroutines fetching repositories
+	// concurrently.
+	MaxFetcherWorkers uint `json:"max_fetcher_workers"`
+
+	// TarRepos, when true, archives each repository into a ".tar" file
+	// after it has been fetched.
+	TarRepos bool `json:"tar_repos"`
+
+	// ThrottlerWaitTime, SlidingWindowSize and LeakInterval configure the
+	// errbag throttler used to detect repeated, likely systemic, errors.
+	ThrottlerWaitTime string `json:"throttler_wait_time"`
+	SlidingWindowSize int    `json:"sliding_window_size"`
+	LeakInterval      string `json:"leak_interval"`
+
+	// EnableCache, when true, routes every clone through a shared bare
+	// mirror cache so that forks of the same upstream only fetch objects
+	// once. See package cache.
+	EnableCache bool `json:"enable_cache"`
+
+	// CacheDir is the directory under which the bare mirror cache is
+	// kept, one subdirectory per unique clone URL. Only meaningful when
+	// EnableCache is true.
+	CacheDir string `json:"cache_dir"`
+
+	// MinFetchPeriod is the minimum duration, in time.ParseDuration
+	// format, between two fetches of the same cache entry. Requests
+	// coming in before this period has elapsed reuse the existing
+	// mirror without hitting the network.
+	MinFetchPeriod string `json:"min_fetch_period"`
+
+	// ControlSocket, when set, is the path of a unix domain socket
+	// crawld listens on for runtime control commands (pause, resume,
+	// sync, skip, status, reload-config). See package control.
+	ControlSocket string `json:"control_socket"`
+
+	// ControlAddr, when set, is an additional "host:port" TCP address to
+	// listen for the same control commands on.
+	ControlAddr string `json:"control_addr"`
+
+	// ShutdownTimeout is the maximum duration, in time.ParseDuration
+	// format, main waits for the fetcher's workers to wind down after a
+	// SIGINT before exiting anyway. Git clones and updates still running
+	// when the signal arrives are aborted right away rather than left to
+	// finish; mercurial operations, tar and cleanup steps run to completion.
+	ShutdownTimeout string `json:"shutdown_timeout"`
+
+	// HTTPAddr, when set, is the "host:port" address crawld serves
+	// /status and /metrics on. See package puller.
+	HTTPAddr string `json:"http_addr"`
+
+	// CloneOptions trims clones down to only the history the code-mining
+	// use case actually needs. See package repo.
+	CloneOptions CloneOptionsConfig `json:"clone_options"`
+}
+
+// CloneOptionsConfig configures how much of a repository's history crawld
+// actually fetches, and guards against unexpectedly large repositories.
+type CloneOptionsConfig struct {
+	// Depth, if > 0, performs a shallow clone fetching only the last Depth
+	// commits (or, for mercurial, up to changeset Depth).
+	Depth int `json:"depth"`
+
+	// SingleBranch restricts the clone to the remote's default branch.
+	SingleBranch bool `json:"single_branch"`
+
+	// SparsePaths, if non-empty, checks out only these paths using a
+	// cone-mode sparse-checkout. Git only.
+	SparsePaths []string `json:"sparse_paths"`
+
+	// Submodules, when true, recurses into submodules while cloning. Git
+	// only; off by default, matching plain "git clone".
+	Submodules bool `json:"submodules"`
+
+	// LFS, when true, pulls Git LFS objects after cloning.
+	LFS bool `json:"lfs"`
+
+	// DeepenOnUpdate, when true, turns a shallow clone into a full one the
+	// first time it is updated, via "git fetch --unshallow".
+	DeepenOnUpdate bool `json:"deepen_on_update"`
+
+	// MaxRepoSizeMB, if > 0, aborts and discards a clone that grows past
+	// this size, in megabytes. For git this is the byte count received, as
+	// reported on its progress output; for mercurial it is the size of the
+	// working copy once the clone has completed.
+	MaxRepoSizeMB int64 `json:"max_repo_size_mb"`
+}
+
+// ReadConfig reads and parses the configuration file located at path.
+func ReadConfig(path string) (*Config, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(Config)
+	if err := json.Unmarshal(bs, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package control implements a small runtime control surface for crawld: a
+// Controller that repoWorker consults to pause/resume, to learn about
+// out-of-band sync/skip requests, and to know when a graceful shutdown has
+// been requested, plus a line-oriented listener (see Listen) that drives it
+// from the outside.
+package control
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Status is a snapshot of the fetcher's runtime state, as reported by the
+// "status" command.
+type Status struct {
+	Paused      bool
+	QueueDepth  int
+	Workers     int
+	BusyWorkers int
+}
+
+// WorkerStatus describes one repository currently being cloned or updated,
+// as reported per active worker by the "status" command.
+type WorkerStatus struct {
+	RepoID  uint64
+	URL     string
+	Phase   string
+	Elapsed time.Duration
+	Bytes   int64
+}
+
+// Controller coordinates runtime control of the repository fetcher. It is
+// driven by a tiny "command:arg" line protocol (see Listen), modeled on the
+// one used by git mirror controllers, so that shell scripts can drive it
+// with nc.
+type Controller struct {
+	paused int32 // atomic bool, gates "fetcher" task dispatch
+
+	syncRequests chan uint64
+
+	mu           sync.Mutex
+	skipped      map[uint64]bool
+	status       Status
+	workerStatus func() []WorkerStatus
+	reloadCh     chan struct{}
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+}
+
+// New returns a ready to use Controller.
+func New() *Controller {
+	return &Controller{
+		syncRequests: make(chan uint64, 64),
+		skipped:      make(map[uint64]bool),
+		reloadCh:     make(chan struct{}, 1),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Pause gates task dispatch for the "fetcher" subsystem: workers finish the
+// repo they are currently processing but won't start another one.
+func (c *Controller) Pause() { atomic.StoreInt32(&c.paused, 1) }
+
+// Resume undoes Pause.
+func (c *Controller) Resume() { atomic.StoreInt32(&c.paused, 0) }
+
+// Paused reports whether the fetcher is currently paused.
+func (c *Controller) Paused() bool { return atomic.LoadInt32(&c.paused) == 1 }
+
+// RequestSync asks the fetcher to process repository id out of band, as
+// soon as a worker of the running fetch pass is free, ahead of the rest of
+// that pass's queue. A request made while no pass is running waits for the
+// next pass to start.
+func (c *Controller) RequestSync(id uint64) {
+	select {
+	case c.syncRequests <- id:
+	default:
+		glog.Warningf("control: sync request for repo %d dropped, queue is full", id)
+	}
+}
+
+// SyncRequests returns the channel repoWorker reads out-of-band sync
+// requests from.
+func (c *Controller) SyncRequests() <-chan uint64 { return c.syncRequests }
+
+// RequestSkip marks id so that the fetcher skips it the next time it is
+// about to be cloned or updated.
+func (c *Controller) RequestSkip(id uint64) {
+	c.mu.Lock()
+	c.skipped[id] = true
+	c.mu.Unlock()
+}
+
+// Skipped reports whether id was asked to be skipped, consuming the
+// request so it only applies once.
+func (c *Controller) Skipped(id uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.skipped[id] {
+		delete(c.skipped, id)
+		return true
+	}
+	return false
+}
+
+// RequestReload asks main to reload the configuration file.
+func (c *Controller) RequestReload() {
+	select {
+	case c.reloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// ReloadRequested returns the channel "reload-config" requests are
+// delivered on.
+func (c *Controller) ReloadRequested() <-chan struct{} { return c.reloadCh }
+
+// SetStatus updates the status reported by the "status" command. It is
+// called periodically by repoWorker.
+func (c *Controller) SetStatus(s Status) {
+	c.mu.Lock()
+	c.status = s
+	c.mu.Unlock()
+}
+
+// Status returns the last status reported via SetStatus.
+func (c *Controller) Status() Status {
+	c.mu.Lock()
+	s := c.status
+	c.mu.Unlock()
+
+	s.Paused = c.Paused()
+	return s
+}
+
+// SetWorkerStatusFunc registers f as the source of per-worker detail (the
+// repository being processed, its URL, elapsed time and bytes transferred
+// so far) reported by the "status" command. It is called by main, once the
+// puller registry exists.
+func (c *Controller) SetWorkerStatusFunc(f func() []WorkerStatus) {
+	c.mu.Lock()
+	c.workerStatus = f
+	c.mu.Unlock()
+}
+
+// WorkerStatuses returns the current per-worker detail, or nil if
+// SetWorkerStatusFunc was never called.
+func (c *Controller) WorkerStatuses() []WorkerStatus {
+	c.mu.Lock()
+	f := c.workerStatus
+	c.mu.Unlock()
+
+	if f == nil {
+		return nil
+	}
+	return f()
+}
+
+// Stop asks every holder of a Stopped channel to wind down gracefully. It
+// is safe to call more than once.
+func (c *Controller) Stop() {
+	c.stopOnce.Do(func() { close(c.stopCh) })
+}
+
+// Stopped returns a channel that is closed once Stop has been called.
+func (c *Controller) Stopped() <-chan struct{} { return c.stopCh }
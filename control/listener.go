@@ -0,0 +1,125 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package control
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Listen starts accepting control connections on network/addr (e.g.
+// "unix", "/var/run/crawld.sock" or "tcp", "127.0.0.1:4600") and serves
+// them in the background until the returned listener is closed.
+func Listen(network, addr string, c *Controller) (net.Listener, error) {
+	if network == "unix" {
+		// a stale socket file from a previous, uncleanly stopped run
+		// would otherwise make the bind fail.
+		_ = os.Remove(addr)
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	go acceptLoop(l, c)
+
+	return l, nil
+}
+
+func acceptLoop(l net.Listener, c *Controller) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			// the listener was closed as part of shutdown.
+			return
+		}
+		go serve(conn, c)
+	}
+}
+
+func serve(conn net.Conn, c *Controller) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintln(conn, handle(line, c)); err != nil {
+			glog.Warning("control: cannot write reply: " + err.Error())
+			return
+		}
+	}
+}
+
+// handle parses a single "command" or "command:arg" line and applies it to
+// c, returning the line to write back to the client.
+func handle(line string, c *Controller) string {
+	cmd, arg := line, ""
+	if idx := strings.IndexByte(line, ':'); idx >= 0 {
+		cmd, arg = line[:idx], line[idx+1:]
+	}
+
+	switch cmd {
+	case "pause":
+		if arg != "fetcher" {
+			return "ERR unknown subsystem " + arg
+		}
+		c.Pause()
+		return "OK"
+	case "resume":
+		if arg != "fetcher" {
+			return "ERR unknown subsystem " + arg
+		}
+		c.Resume()
+		return "OK"
+	case "sync":
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return "ERR invalid repo id " + arg
+		}
+		c.RequestSync(id)
+		return "OK"
+	case "skip":
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return "ERR invalid repo id " + arg
+		}
+		c.RequestSkip(id)
+		return "OK"
+	case "reload-config":
+		c.RequestReload()
+		return "OK"
+	case "status":
+		return formatStatus(c.Status(), c.WorkerStatuses())
+	default:
+		return "ERR unknown command " + cmd
+	}
+}
+
+// formatStatus renders s and the per-worker detail in workers as a single
+// reply line: one "repo=... url=... phase=... elapsed=... bytes=..." group
+// per repository currently being cloned or updated.
+func formatStatus(s Status, workers []WorkerStatus) string {
+	out := fmt.Sprintf("OK paused=%v queue_depth=%d workers=%d busy_workers=%d",
+		s.Paused, s.QueueDepth, s.Workers, s.BusyWorkers)
+
+	for _, w := range workers {
+		out += fmt.Sprintf(" repo=%d url=%s phase=%s elapsed=%s bytes=%d",
+			w.RepoID, w.URL, w.Phase, w.Elapsed.Round(time.Second), w.Bytes)
+	}
+
+	return out
+}
@@ -0,0 +1,102 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package control
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHandlePauseResume(t *testing.T) {
+	c := New()
+
+	if got := handle("pause:fetcher", c); got != "OK" {
+		t.Errorf("handle(pause:fetcher) = %q, want OK", got)
+	}
+	if !c.Paused() {
+		t.Error("Paused() = false after pause:fetcher")
+	}
+
+	if got := handle("resume:fetcher", c); got != "OK" {
+		t.Errorf("handle(resume:fetcher) = %q, want OK", got)
+	}
+	if c.Paused() {
+		t.Error("Paused() = true after resume:fetcher")
+	}
+}
+
+func TestHandleUnknownSubsystem(t *testing.T) {
+	c := New()
+
+	got := handle("pause:crawler", c)
+	if !strings.HasPrefix(got, "ERR") {
+		t.Errorf("handle(pause:crawler) = %q, want an ERR reply", got)
+	}
+}
+
+func TestHandleSyncAndSkip(t *testing.T) {
+	c := New()
+
+	if got := handle("sync:42", c); got != "OK" {
+		t.Errorf("handle(sync:42) = %q, want OK", got)
+	}
+	select {
+	case id := <-c.SyncRequests():
+		if id != 42 {
+			t.Errorf("sync request id = %d, want 42", id)
+		}
+	default:
+		t.Error("sync:42 did not enqueue a sync request")
+	}
+
+	if got := handle("skip:7", c); got != "OK" {
+		t.Errorf("handle(skip:7) = %q, want OK", got)
+	}
+	if !c.Skipped(7) {
+		t.Error("Skipped(7) = false after skip:7")
+	}
+
+	if got := handle("sync:notanumber", c); !strings.HasPrefix(got, "ERR") {
+		t.Errorf("handle(sync:notanumber) = %q, want an ERR reply", got)
+	}
+}
+
+func TestHandleReloadConfig(t *testing.T) {
+	c := New()
+
+	if got := handle("reload-config", c); got != "OK" {
+		t.Errorf("handle(reload-config) = %q, want OK", got)
+	}
+
+	select {
+	case <-c.ReloadRequested():
+	default:
+		t.Error("reload-config did not signal ReloadRequested()")
+	}
+}
+
+func TestHandleStatus(t *testing.T) {
+	c := New()
+	c.SetStatus(Status{QueueDepth: 3, Workers: 2, BusyWorkers: 1})
+	c.SetWorkerStatusFunc(func() []WorkerStatus {
+		return []WorkerStatus{{RepoID: 5, URL: "https://example.com/foo.git", Phase: "cloning"}}
+	})
+
+	got := handle("status", c)
+	for _, want := range []string{"queue_depth=3", "workers=2", "busy_workers=1", "repo=5", "url=https://example.com/foo.git", "phase=cloning"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("handle(status) = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestHandleUnknownCommand(t *testing.T) {
+	c := New()
+
+	got := handle("frobnicate", c)
+	if !strings.HasPrefix(got, "ERR") {
+		t.Errorf("handle(frobnicate) = %q, want an ERR reply", got)
+	}
+}
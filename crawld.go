@@ -10,6 +10,7 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
@@ -18,14 +19,18 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/glog"
 	_ "github.com/lib/pq"
 
+	"github.com/DevMine/crawld/cache"
 	"github.com/DevMine/crawld/config"
+	"github.com/DevMine/crawld/control"
 	"github.com/DevMine/crawld/crawlers"
 	"github.com/DevMine/crawld/errbag"
+	"github.com/DevMine/crawld/puller"
 	"github.com/DevMine/crawld/repo"
 	"github.com/DevMine/crawld/tar"
 )
@@ -33,12 +38,125 @@ import (
 // extend this structure later if required but for now the repository id sufficient
 type dbRepo struct {
 	repo.Repo
-	id uint64
+	id  uint64
+	vcs string
 }
 
 // channel used to communicate repositories IDs
 var idChan chan uint64
 
+// errSizeCapped is returned internally by withCloneLimits when a clone was
+// killed, or found to be, over cfg.CloneOptions.MaxRepoSizeMB.
+var errSizeCapped = errors.New("crawld: repo exceeded the size cap")
+
+// lastFetchedID and nextFetchAt back the "last_fetched_id" and
+// "next_fetch_time" fields of the /status endpoint; they are updated by
+// repoWorker and the idChan writer goroutine and read by the HTTP server,
+// so every access goes through statusMu.
+var (
+	statusMu      sync.Mutex
+	lastFetchedID uint64
+	nextFetchAt   time.Time
+)
+
+func setLastFetchedID(id uint64) {
+	statusMu.Lock()
+	lastFetchedID = id
+	statusMu.Unlock()
+}
+
+func setNextFetchAt(t time.Time) {
+	statusMu.Lock()
+	nextFetchAt = t
+	statusMu.Unlock()
+}
+
+func getStatusTimes() (uint64, time.Time) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return lastFetchedID, nextFetchAt
+}
+
+// cfgStore holds the *config.Config "reload-config" should apply, so that
+// repoWorker can pick up a fresh one at the start of its next pass.
+// Database, Crawlers, EnableCache/CacheDir, ControlSocket/ControlAddr and
+// HTTPAddr are only read once at startup and need a restart to change;
+// FetchTimeInterval, FetchLanguages, CloneDir, MaxFetcherWorkers, TarRepos
+// and CloneOptions are re-read on every pass.
+var cfgStore atomic.Value
+
+func currentConfig() *config.Config {
+	if v := cfgStore.Load(); v != nil {
+		return v.(*config.Config)
+	}
+	return nil
+}
+
+// activeLocks tracks the repo locks currently held by in-flight clone/update
+// cycles, so that a graceful signal shutdown can release them even though
+// the goroutines holding them never get a chance to run their defers.
+var (
+	activeLocksMu sync.Mutex
+	activeLocks   = map[string]*repo.Lock{}
+)
+
+// withRepoLock acquires an exclusive lock on r's working directory for the
+// duration of fn, which should wrap the whole clone/update/tar/extract
+// cycle for that repository. If the lock is already held, presumably by
+// another crawld process or an out-of-band admin command, it is recorded in
+// errBag and the repository is skipped for this pass rather than blocking.
+func withRepoLock(r dbRepo, errBag *errbag.ErrBag, fn func() error) error {
+	_, lockfile, err := repo.WorkDir(r.vcs, r.AbsPath())
+	if err != nil {
+		errBag.Record(err)
+		return err
+	}
+
+	l, err := repo.NewLock(lockfile)
+	if err != nil {
+		errBag.Record(err)
+		return err
+	}
+	defer l.Close()
+
+	ok, err := l.TryLock()
+	if err != nil {
+		errBag.Record(err)
+		return err
+	}
+	if !ok {
+		err = fmt.Errorf("%s is locked by another process, skipping this pass", r.AbsPath())
+		glog.Info(err)
+		errBag.Record(err)
+		return err
+	}
+
+	activeLocksMu.Lock()
+	activeLocks[lockfile] = l
+	activeLocksMu.Unlock()
+	defer func() {
+		activeLocksMu.Lock()
+		delete(activeLocks, lockfile)
+		activeLocksMu.Unlock()
+	}()
+
+	return fn()
+}
+
+// releaseActiveLocks releases every lock currently held by an in-flight
+// clone/update cycle. It is called from the signal handler so that a
+// graceful shutdown does not leave stale locks behind.
+func releaseActiveLocks() {
+	activeLocksMu.Lock()
+	defer activeLocksMu.Unlock()
+
+	for path, l := range activeLocks {
+		if err := l.Close(); err != nil {
+			glog.Warning("cannot release lock (" + path + "): " + err.Error())
+		}
+	}
+}
+
 func crawlingWorker(cs []crawlers.Crawler, crawlingInterval time.Duration) {
 	for {
 		var wg sync.WaitGroup
@@ -59,43 +177,256 @@ func crawlingWorker(cs []crawlers.Crawler, crawlingInterval time.Duration) {
 	}
 }
 
-func repoWorker(db *sql.DB, cfg *config.Config, startId uint64, errBag *errbag.ErrBag) {
+func repoWorker(db *sql.DB, cfg *config.Config, startId uint64, errBag *errbag.ErrBag,
+	controller *control.Controller, registry *puller.Registry, counters *puller.Counters, done chan<- struct{}) {
+
+	defer close(done)
 
 	fetchInterval, err := time.ParseDuration(cfg.FetchTimeInterval)
 	if err != nil {
 		fatal(err)
 	}
 
-	clone := func(r repo.Repo) error {
-		glog.Infof("cloning %s into %s\n", r.URL(), r.AbsPath())
-		if err := r.Clone(); err != nil {
-			glog.Errorf("impossible to clone %s in %s ("+err.Error()+") skipping", r.URL(), r.AbsPath())
-			errBag.Record(err)
+	cloneOpts := repo.CloneOptions{
+		Depth:        cfg.CloneOptions.Depth,
+		SingleBranch: cfg.CloneOptions.SingleBranch,
+		SparsePaths:  cfg.CloneOptions.SparsePaths,
+		Submodules:   cfg.CloneOptions.Submodules,
+		LFS:          cfg.CloneOptions.LFS,
+	}
+
+	var repoCache *cache.Cache
+	if cfg.EnableCache {
+		minFetchPeriod, err := time.ParseDuration(cfg.MinFetchPeriod)
+		if err != nil {
+			fatal(err)
+		}
+
+		if repoCache, err = cache.New(cfg.CacheDir, minFetchPeriod); err != nil {
+			fatal(err)
+		}
+	}
+
+	// withProgress wires state's ProgressWriter into r, if its backend
+	// supports streaming progress, for the duration of fn. It also arms
+	// cancellation on controller.Stopped(), if the backend supports it, so
+	// that a graceful shutdown kills the in-flight VCS process instead of
+	// just releasing its lock out from under it.
+	withProgress := func(r dbRepo, state *puller.SharedPullerState, fn func() error) error {
+		if pr, ok := r.Repo.(repo.ProgressReporter); ok {
+			pr.SetProgressOutput(puller.NewProgressWriter(state))
+			defer pr.SetProgressOutput(nil)
+		}
+		if c, ok := r.Repo.(repo.Canceler); ok {
+			c.SetCancel(controller.Stopped())
+			defer c.SetCancel(nil)
+		}
+		return fn()
+	}
+
+	// withCloneLimits behaves like withProgress, but additionally enforces
+	// cfg.CloneOptions.MaxRepoSizeMB against state's live byte count while
+	// fn runs, so an oversized clone is killed mid-transfer instead of
+	// filling the disk before anyone notices. It needs repo.Canceler to do
+	// that; backends that don't implement it (e.g. mercurial) fall back to
+	// checking the final size once fn returns, which can't stop the bytes
+	// from having already landed on disk. Either way, an over-cap clone is
+	// reported as errSizeCapped.
+	withCloneLimits := func(r dbRepo, state *puller.SharedPullerState, fn func() error) error {
+		if pr, ok := r.Repo.(repo.ProgressReporter); ok {
+			pr.SetProgressOutput(puller.NewProgressWriter(state))
+			defer pr.SetProgressOutput(nil)
+		}
+
+		maxBytes := cfg.CloneOptions.MaxRepoSizeMB * 1024 * 1024
+
+		c, ok := r.Repo.(repo.Canceler)
+		if !ok {
+			if err := fn(); err != nil {
+				return err
+			}
+			if maxBytes <= 0 {
+				return nil
+			}
+			sizeMB, err := dirSizeMB(r.AbsPath())
+			if err != nil {
+				glog.Warningf("cannot compute size of %s (%s)", r.AbsPath(), err.Error())
+				return nil
+			}
+			if sizeMB > cfg.CloneOptions.MaxRepoSizeMB {
+				return errSizeCapped
+			}
+			return nil
+		}
+
+		if maxBytes <= 0 {
+			c.SetCancel(controller.Stopped())
+			defer c.SetCancel(nil)
+			return fn()
+		}
+
+		abort := make(chan struct{})
+		done := make(chan struct{})
+		defer close(done)
+		capped := make(chan struct{}, 1)
+
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-controller.Stopped():
+					close(abort)
+					return
+				case <-ticker.C:
+					if state.Snapshot().Bytes > maxBytes {
+						capped <- struct{}{}
+						close(abort)
+						return
+					}
+				}
+			}
+		}()
+
+		c.SetCancel(abort)
+		defer c.SetCancel(nil)
+
+		err := fn()
+
+		select {
+		case <-capped:
+			return errSizeCapped
+		default:
 			return err
 		}
+	}
+
+	// cloneFromCache tries to produce r's working copy from the shared
+	// bare mirror cache. It reports whether it succeeded; on failure the
+	// caller should fall back to a direct clone, unless the failure was
+	// errSizeCapped, which the caller should treat as final since a
+	// direct clone would just hit the same cap again.
+	cloneFromCache := func(gc repo.CacheCloner, r dbRepo, state *puller.SharedPullerState) (bool, error) {
+		cacheDir, err := repoCache.EnsureFetched(r.id, r.URL())
+		if err != nil {
+			glog.Warningf("cache fetch failed for %s (%s), falling back to a direct clone", r.URL(), err.Error())
+			errBag.Record(err)
+			return false, nil
+		}
+
+		if err := withCloneLimits(r, state, func() error { return gc.CloneFromCache(cacheDir) }); err != nil {
+			if err == errSizeCapped {
+				return false, err
+			}
+			glog.Warningf("cannot clone %s from cache (%s), falling back to a direct clone", r.URL(), err.Error())
+			errBag.Record(err)
+			return false, nil
+		}
+
+		return true, nil
+	}
+
+	// abortOversizedClone records and discards a clone that exceeded
+	// cfg.CloneOptions.MaxRepoSizeMB, whether it was killed mid-transfer or
+	// caught by the post-hoc fallback check.
+	abortOversizedClone := func(r dbRepo, state *puller.SharedPullerState) error {
+		err := fmt.Errorf("%s exceeded the %dMB cap, aborting the clone",
+			r.AbsPath(), cfg.CloneOptions.MaxRepoSizeMB)
+		glog.Warning(err)
+		errBag.Record(err)
+		state.SetError(err)
+		counters.IncFailed("size-cap")
+		if err2 := os.RemoveAll(r.AbsPath()); err2 != nil {
+			glog.Errorf("cannot remove oversized clone %s (%s)", r.AbsPath(), err2.Error())
+		}
+		return err
+	}
+
+	clone := func(r dbRepo, state *puller.SharedPullerState) error {
+		glog.Infof("cloning %s into %s\n", r.URL(), r.AbsPath())
+		state.SetPhase(puller.PhaseCloning)
+
+		cloned := false
+		if repoCache != nil {
+			if gc, ok := r.Repo.(repo.CacheCloner); ok {
+				var err error
+				cloned, err = cloneFromCache(gc, r, state)
+				if err == errSizeCapped {
+					return abortOversizedClone(r, state)
+				}
+			}
+		}
+
+		if !cloned {
+			if err := withCloneLimits(r, state, r.Clone); err != nil {
+				if err == repo.ErrAborted {
+					glog.Infof("clone of %s aborted by shutdown request", r.AbsPath())
+					state.SetError(err)
+					return err
+				}
+				if err == errSizeCapped {
+					return abortOversizedClone(r, state)
+				}
+				glog.Errorf("impossible to clone %s in %s ("+err.Error()+") skipping", r.URL(), r.AbsPath())
+				errBag.Record(err)
+				state.SetError(err)
+				counters.IncFailed("clone")
+				return err
+			}
+		}
+
+		counters.IncCloned()
 		return nil
 	}
 
-	update := func(r repo.Repo) error {
+	update := func(r dbRepo, state *puller.SharedPullerState) error {
 		glog.Infof("updating %s\n", r.AbsPath())
-		if err := r.Update(); err != nil {
+		state.SetPhase(puller.PhaseUpdating)
+
+		if err := withProgress(r, state, r.Update); err != nil {
+			if err == repo.ErrAborted {
+				// a graceful shutdown killed the pull mid-flight: the
+				// working copy is still the one from the last successful
+				// update, so leave it alone instead of wiping it.
+				glog.Infof("update of %s aborted by shutdown request", r.AbsPath())
+				state.SetError(err)
+				return err
+			}
+
 			glog.Warningf("impossible to update %s ("+err.Error()+")", r.AbsPath())
 			errBag.Record(err)
+			state.SetError(err)
 
 			// we just want to skip on a network error
 			if err == repo.ErrNetwork {
+				counters.IncFailed("network")
 				return err
 			}
 
 			// delete and reclone then
+			state.IncRetries()
+			counters.IncFailed("update")
 			glog.Infof("attempting to re-clone %s", r.AbsPath())
 			if err2 := os.RemoveAll(r.AbsPath()); err2 != nil {
 				glog.Errorf("cannot remove %s("+err2.Error()+")", r.AbsPath())
 				errBag.Record(err)
 				return err
 			}
-			return clone(r)
+			return clone(r, state)
+		}
+
+		if cfg.CloneOptions.DeepenOnUpdate {
+			if d, ok := r.Repo.(repo.Deepener); ok {
+				if err := d.Deepen(); err != nil {
+					glog.Warningf("cannot deepen %s (%s)", r.AbsPath(), err.Error())
+					errBag.Record(err)
+				}
+			}
 		}
+
+		counters.IncUpdated()
 		return nil
 	}
 
@@ -108,8 +439,35 @@ func repoWorker(db *sql.DB, cfg *config.Config, startId uint64, errBag *errbag.E
 	}
 
 	for {
+		select {
+		case <-controller.Stopped():
+			glog.Info("shutdown requested, stopping the repositories fetcher")
+			return
+		default:
+		}
+
+		// "reload-config" lands here: pick up a fresh *config.Config, if
+		// one was stored since the last pass, for the fields this loop
+		// re-reads every time anyway.
+		if newCfg := currentConfig(); newCfg != nil && newCfg != cfg {
+			cfg = newCfg
+			if d, err := time.ParseDuration(cfg.FetchTimeInterval); err == nil {
+				fetchInterval = d
+			} else {
+				glog.Warning("control: invalid fetch_time_interval in reloaded config, keeping the previous value")
+			}
+			cloneOpts = repo.CloneOptions{
+				Depth:        cfg.CloneOptions.Depth,
+				SingleBranch: cfg.CloneOptions.SingleBranch,
+				SparsePaths:  cfg.CloneOptions.SparsePaths,
+				Submodules:   cfg.CloneOptions.Submodules,
+				LFS:          cfg.CloneOptions.LFS,
+			}
+			glog.Info("control: applied reloaded configuration")
+		}
+
 		glog.Info("starting the repositories fetcher")
-		repos, err := getAllRepos(db, startId, cfg.FetchLanguages, cfg.CloneDir)
+		repos, err := getAllRepos(db, startId, cfg.FetchLanguages, cfg.CloneDir, cloneOpts)
 		if err != nil {
 			fatal(err)
 		}
@@ -119,6 +477,12 @@ func repoWorker(db *sql.DB, cfg *config.Config, startId uint64, errBag *errbag.E
 		tasks := make(chan dbRepo, len(repos))
 		var wg sync.WaitGroup
 
+		controller.SetStatus(control.Status{
+			QueueDepth:  len(repos),
+			Workers:     int(cfg.MaxFetcherWorkers),
+			BusyWorkers: registry.Len(),
+		})
+
 		for _, r := range repos {
 			tasks <- r
 		}
@@ -130,48 +494,143 @@ func repoWorker(db *sql.DB, cfg *config.Config, startId uint64, errBag *errbag.E
 		for w := uint(0); w < cfg.MaxFetcherWorkers; w++ {
 			wg.Add(1)
 			go func() {
-				for r := range tasks {
-					// if we have a tar archive, we need to extract it
-					archive := r.AbsPath() + ".tar"
-					if _, err = os.Stat(archive); err == nil {
-						if err = tar.ExtractInPlace(archive); err != nil {
-							glog.Warning("impossible to extract the tar archive (" + archive + ")" +
-								", cannot update the repository: " + err.Error())
-							// attempt to remove the eventual mess
-							_ = os.Remove(archive)
-							_ = os.RemoveAll(r.AbsPath())
-						}
+				defer wg.Done()
+
+				// processRepo runs the whole clone/update/tar/cleanup
+				// cycle for r, whether it came off the regular tasks
+				// queue or an out-of-band "sync:<repo_id>" request.
+				processRepo := func(r dbRepo) {
+					if controller.Skipped(r.id) {
+						glog.Infof("skipping repo %d on request\n", r.id)
+						return
 					}
 
-					if _, err := os.Stat(r.AbsPath()); os.IsNotExist(err) || isDirEmpty(r.AbsPath()) {
-						if err = clone(r); err != nil {
-							continue
+					state := registry.Track(r.id, r.URL())
+					fetchStart := time.Now()
+
+					if err := withRepoLock(r, errBag, func() error {
+						// if we have a tar archive, we need to extract it
+						archive := r.AbsPath() + ".tar"
+						if _, err := os.Stat(archive); err == nil {
+							state.SetPhase(puller.PhaseExtracting)
+							if err := tar.ExtractInPlace(archive); err != nil {
+								glog.Warning("impossible to extract the tar archive (" + archive + ")" +
+									", cannot update the repository: " + err.Error())
+								// attempt to remove the eventual mess
+								_ = os.Remove(archive)
+								_ = os.RemoveAll(r.AbsPath())
+							}
 						}
-					} else {
-						if err = update(r); err != nil {
-							continue
+
+						if _, err := os.Stat(r.AbsPath()); os.IsNotExist(err) || isDirEmpty(r.AbsPath()) {
+							if err := clone(r, state); err != nil {
+								return err
+							}
+						} else {
+							if err := update(r, state); err != nil {
+								return err
+							}
 						}
-					}
 
-					if cfg.TarRepos {
-						createArchive(r.AbsPath())
-					}
+						if cfg.TarRepos {
+							state.SetPhase(puller.PhaseTarring)
+							createArchive(r.AbsPath())
+						}
+
+						state.SetPhase(puller.PhaseCleanup)
+						if err := r.Cleanup(); err != nil {
+							glog.Warning(err)
+						}
 
-					if err = r.Cleanup(); err != nil {
-						glog.Warning(err)
+						return nil
+					}); err != nil {
+						counters.ObserveFetchDuration(time.Since(fetchStart))
+						registry.Untrack(r.id)
+						return
 					}
 
+					counters.ObserveFetchDuration(time.Since(fetchStart))
+					registry.Untrack(r.id)
+
 					// notify we're done with this repository
 					idChan <- r.id
 				}
-				wg.Done()
+
+				for {
+					// "pause:fetcher" gates dispatch here: a worker
+					// finishes whatever it is doing but won't pick up a
+					// new repository while paused.
+					for controller.Paused() {
+						select {
+						case <-time.After(500 * time.Millisecond):
+						case <-controller.Stopped():
+							return
+						}
+					}
+
+					syncRepo := func(id uint64) {
+						// served as soon as a worker is free, regardless
+						// of whether it is still in the middle of this
+						// pass's regular tasks queue.
+						r, err := getRepoByID(db, id, cfg.CloneDir, cloneOpts)
+						if err != nil {
+							glog.Warningf("control: cannot sync repo %d (%s)\n", id, err.Error())
+							errBag.Record(err)
+							return
+						}
+						processRepo(r)
+					}
+
+					// tasks is closed from the moment the pass starts (it
+					// is only ever filled once, up front), so a read off
+					// it is "ready" in a select for the entire pass, not
+					// just once genuinely drained. Give Stopped/
+					// SyncRequests priority with a non-blocking check
+					// first, so a sync request isn't starved by that
+					// always-ready case, and only fall back to treating
+					// an exhausted tasks as "this pass is done" once
+					// neither has anything waiting.
+					select {
+					case <-controller.Stopped():
+						return
+					case id := <-controller.SyncRequests():
+						syncRepo(id)
+						continue
+					default:
+					}
+
+					select {
+					case <-controller.Stopped():
+						return
+					case id := <-controller.SyncRequests():
+						syncRepo(id)
+					case r, ok := <-tasks:
+						if !ok {
+							return
+						}
+						processRepo(r)
+					}
+				}
 			}()
 		}
 
 		wg.Wait()
 
+		select {
+		case <-controller.Stopped():
+			glog.Info("shutdown requested, stopping the repositories fetcher")
+			return
+		default:
+		}
+
 		glog.Infof("waiting for %v before re-starting the fetcher.\n", fetchInterval)
-		<-time.After(fetchInterval)
+		setNextFetchAt(time.Now().Add(fetchInterval))
+		select {
+		case <-time.After(fetchInterval):
+		case <-controller.Stopped():
+			glog.Info("shutdown requested, stopping the repositories fetcher")
+			return
+		}
 	}
 }
 
@@ -184,7 +643,28 @@ func isDirEmpty(path string) bool {
 	return len(fis) == 0
 }
 
-func getAllRepos(db *sql.DB, startId uint64, langs []string, basePath string) ([]dbRepo, error) {
+// dirSizeMB returns the total size of the regular files under path, in
+// megabytes, used to enforce config.CloneOptionsConfig.MaxRepoSizeMB.
+func dirSizeMB(path string) (int64, error) {
+	var bytes int64
+
+	err := filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			bytes += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return bytes / (1024 * 1024), nil
+}
+
+func getAllRepos(db *sql.DB, startId uint64, langs []string, basePath string, opts repo.CloneOptions) ([]dbRepo, error) {
 	inClause := fmt.Sprintf("WHERE id >= %d", startId)
 	if langs != nil && len(langs) > 0 {
 		// Quote languages.
@@ -213,18 +693,39 @@ func getAllRepos(db *sql.DB, startId uint64, langs []string, basePath string) ([
 
 		var newRepo repo.Repo
 		var err error
-		newRepo, err = repo.New(vcs, filepath.Join(basePath, clonePath), cloneURL)
+		newRepo, err = repo.New(vcs, filepath.Join(basePath, clonePath), cloneURL, opts)
 		if err != nil {
 			glog.Error(err)
 			continue
 		}
 
-		repos = append(repos, dbRepo{Repo: newRepo, id: id})
+		repos = append(repos, dbRepo{Repo: newRepo, id: id, vcs: vcs})
 	}
 
 	return repos, nil
 }
 
+// getRepoByID fetches a single repository by id, bypassing the usual
+// language filtering. It backs the control socket's "sync:<repo_id>"
+// command.
+func getRepoByID(db *sql.DB, id uint64, basePath string, opts repo.CloneOptions) (dbRepo, error) {
+	row := db.QueryRow(fmt.Sprintf(
+		"SELECT id, vcs, clone_path, clone_url FROM repositories WHERE id = %d", id))
+
+	var vcs, clonePath, cloneURL string
+	var dbID uint64
+	if err := row.Scan(&dbID, &vcs, &clonePath, &cloneURL); err != nil {
+		return dbRepo{}, err
+	}
+
+	newRepo, err := repo.New(vcs, filepath.Join(basePath, clonePath), cloneURL, opts)
+	if err != nil {
+		return dbRepo{}, err
+	}
+
+	return dbRepo{Repo: newRepo, id: dbID, vcs: vcs}, nil
+}
+
 func checkCloneDir(cloneDir string) error {
 	// check if clone path exists
 	if fi, err := os.Stat(cloneDir); err == nil {
@@ -280,6 +781,7 @@ func main() {
 	if err != nil {
 		fatal(err)
 	}
+	cfgStore.Store(cfg)
 
 	db, err := openDBSession(cfg.Database)
 	if err != nil {
@@ -332,10 +834,101 @@ func main() {
 			startId = 0
 		}
 
+		controller := control.New()
+		if len(cfg.ControlSocket) != 0 {
+			ln, err := control.Listen("unix", cfg.ControlSocket, controller)
+			if err != nil {
+				fatal(err)
+			}
+			defer ln.Close()
+		}
+		if len(cfg.ControlAddr) != 0 {
+			ln, err := control.Listen("tcp", cfg.ControlAddr, controller)
+			if err != nil {
+				fatal(err)
+			}
+			defer ln.Close()
+		}
+
+		// serve "reload-config": re-read the configuration file and publish
+		// it to cfgStore, which repoWorker picks up at the start of its
+		// next pass.
+		go func() {
+			for {
+				select {
+				case <-controller.ReloadRequested():
+					newCfg, err := config.ReadConfig(*configPath)
+					if err != nil {
+						glog.Warning("control: cannot reload config (" + err.Error() + ")")
+						continue
+					}
+					cfgStore.Store(newCfg)
+					glog.Info("control: configuration file reloaded")
+				case <-controller.Stopped():
+					return
+				}
+			}
+		}()
+
+		shutdownTimeout := 30 * time.Second
+		if len(cfg.ShutdownTimeout) != 0 {
+			if d, err := time.ParseDuration(cfg.ShutdownTimeout); err == nil {
+				shutdownTimeout = d
+			} else {
+				glog.Warning("invalid shutdown_timeout, using the default of 30s")
+			}
+		}
+
+		registry := puller.NewRegistry()
+		counters := puller.NewCounters()
+
+		// feed the "status" control command the per-worker detail it was
+		// asked to report: the repo being processed, its URL, elapsed time
+		// and bytes cloned so far.
+		controller.SetWorkerStatusFunc(func() []control.WorkerStatus {
+			states := registry.Snapshot()
+			ws := make([]control.WorkerStatus, len(states))
+			for i, s := range states {
+				ws[i] = control.WorkerStatus{
+					RepoID:  s.RepoID,
+					URL:     s.URL,
+					Phase:   string(s.Phase),
+					Elapsed: time.Since(s.StartTime),
+					Bytes:   s.Bytes,
+				}
+			}
+			return ws
+		})
+
+		if len(cfg.HTTPAddr) != 0 {
+			sp := puller.StatusProvider{
+				QueueDepth:  func() int { return controller.Status().QueueDepth },
+				WorkersBusy: registry.Len,
+				WorkersIdle: func() int {
+					idle := int(cfg.MaxFetcherWorkers) - registry.Len()
+					if idle < 0 {
+						return 0
+					}
+					return idle
+				},
+				LastFetchedID: func() uint64 { id, _ := getStatusTimes(); return id },
+				NextFetchTime: func() time.Time { _, t := getStatusTimes(); return t },
+				ErrBagSize:    errBag.Len,
+			}
+
+			srv := puller.NewServer(cfg.HTTPAddr, registry, counters, sp)
+			go func() {
+				if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					glog.Error("puller HTTP server stopped (" + err.Error() + ")")
+				}
+			}()
+		}
+
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt, os.Kill)
 
 		idChan = make(chan uint64)
+		workerDone := make(chan struct{})
 
 		// this routines writes the last processed repository id in a file, getting it from idChan
 		go func() {
@@ -344,17 +937,30 @@ func main() {
 				glog.Fatal("cannot open file for writing (" + lastFetchedIdFile + "): " + err.Error())
 			}
 
-			// we want to make sure we close the file and do some housekeeping on interruption
+			// we want to make sure we wind down gracefully on interruption:
+			// ask the fetcher to stop (which aborts in-flight git clones and
+			// updates), wait for its workers to return, then do the usual
+			// housekeeping.
 			go func() {
 				<-c
-				fmt.Fprintln(os.Stderr, "caught signal, exiting now...")
+				fmt.Fprintln(os.Stderr, "caught signal, waiting for in-flight repositories to finish...")
+				controller.Stop()
+
+				select {
+				case <-workerDone:
+				case <-time.After(shutdownTimeout):
+					glog.Warning("shutdown deadline exceeded, exiting with repositories still in flight")
+				}
+
 				f.Sync()
 				f.Close()
 				errBag.Deflate()
+				releaseActiveLocks()
 				os.Exit(0)
 			}()
 
 			for id, ok := <-idChan; ok; id, ok = <-idChan {
+				setLastFetchedID(id)
 				if _, err := f.Seek(0, 0); err != nil {
 					glog.Warning("could not write ID to file:", id)
 				} else {
@@ -366,7 +972,7 @@ func main() {
 		}()
 
 		wg.Add(1)
-		go repoWorker(db, cfg, startId, errBag)
+		go repoWorker(db, cfg, startId, errBag, controller, registry, counters, workerDone)
 	}
 
 	// wait until the cows come home saint
@@ -0,0 +1,129 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package puller
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Counters aggregates the counters exposed on /metrics in Prometheus text
+// format.
+type Counters struct {
+	mu      sync.Mutex
+	cloned  uint64
+	updated uint64
+	failed  map[string]uint64
+
+	// fetchBuckets, fetchSum and fetchCount back the fetch_duration_seconds
+	// histogram. Observations are folded into the cumulative bucket counts
+	// as they come in rather than retained, so the histogram's memory and
+	// per-scrape cost stay constant over the life of the process instead of
+	// growing with every clone/update cycle.
+	fetchBuckets []uint64
+	fetchSum     float64
+	fetchCount   uint64
+}
+
+// NewCounters returns a zeroed Counters.
+func NewCounters() *Counters {
+	return &Counters{
+		failed:       make(map[string]uint64),
+		fetchBuckets: make([]uint64, len(histogramBuckets)),
+	}
+}
+
+// IncCloned records a successful clone.
+func (c *Counters) IncCloned() {
+	c.mu.Lock()
+	c.cloned++
+	c.mu.Unlock()
+}
+
+// IncUpdated records a successful update.
+func (c *Counters) IncUpdated() {
+	c.mu.Lock()
+	c.updated++
+	c.mu.Unlock()
+}
+
+// IncFailed records a failed clone or update, tagged with a short reason
+// (e.g. "network", "size-cap", "clone", "update").
+func (c *Counters) IncFailed(reason string) {
+	c.mu.Lock()
+	c.failed[reason]++
+	c.mu.Unlock()
+}
+
+// ObserveFetchDuration records how long a whole clone or update cycle took,
+// feeding the fetch_duration_seconds histogram.
+func (c *Counters) ObserveFetchDuration(d time.Duration) {
+	s := d.Seconds()
+
+	c.mu.Lock()
+	for i, bound := range histogramBuckets {
+		if s <= bound {
+			c.fetchBuckets[i]++
+		}
+	}
+	c.fetchSum += s
+	c.fetchCount++
+	c.mu.Unlock()
+}
+
+// histogramBuckets are the upper bounds, in seconds, of the
+// fetch_duration_seconds histogram.
+var histogramBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}
+
+// renderMetrics formats the counters in Prometheus text exposition format.
+func (c *Counters) renderMetrics(errBagSize int) string {
+	c.mu.Lock()
+	cloned, updated := c.cloned, c.updated
+	failed := make(map[string]uint64, len(c.failed))
+	for reason, n := range c.failed {
+		failed[reason] = n
+	}
+	fetchBuckets := append([]uint64(nil), c.fetchBuckets...)
+	fetchSum, fetchCount := c.fetchSum, c.fetchCount
+	c.mu.Unlock()
+
+	var out string
+
+	out += "# HELP repos_cloned_total Total number of repositories successfully cloned.\n"
+	out += "# TYPE repos_cloned_total counter\n"
+	out += fmt.Sprintf("repos_cloned_total %d\n", cloned)
+
+	out += "# HELP repos_updated_total Total number of repositories successfully updated.\n"
+	out += "# TYPE repos_updated_total counter\n"
+	out += fmt.Sprintf("repos_updated_total %d\n", updated)
+
+	out += "# HELP repos_failed_total Total number of repositories that failed to be fetched.\n"
+	out += "# TYPE repos_failed_total counter\n"
+	reasons := make([]string, 0, len(failed))
+	for reason := range failed {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		out += fmt.Sprintf("repos_failed_total{reason=%q} %d\n", reason, failed[reason])
+	}
+
+	out += "# HELP fetch_duration_seconds Duration of a repository clone/update cycle.\n"
+	out += "# TYPE fetch_duration_seconds histogram\n"
+	for i, bound := range histogramBuckets {
+		out += fmt.Sprintf("fetch_duration_seconds_bucket{le=\"%g\"} %d\n", bound, fetchBuckets[i])
+	}
+	out += fmt.Sprintf("fetch_duration_seconds_bucket{le=\"+Inf\"} %d\n", fetchCount)
+	out += fmt.Sprintf("fetch_duration_seconds_sum %g\n", fetchSum)
+	out += fmt.Sprintf("fetch_duration_seconds_count %d\n", fetchCount)
+
+	out += "# HELP errbag_size Number of errors currently held in the error bag.\n"
+	out += "# TYPE errbag_size gauge\n"
+	out += fmt.Sprintf("errbag_size %d\n", errBagSize)
+
+	return out
+}
@@ -0,0 +1,44 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package puller
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// progressRe matches the cumulative transfer size git and mercurial report
+// on their progress stream, e.g. "Receiving objects: 42% (123/292), 45.67
+// MiB | 3.21 MiB/s".
+var progressRe = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)\s*(KiB|MiB|GiB)\b`)
+
+var unitMultiplier = map[string]int64{
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+}
+
+// ProgressWriter is an io.Writer that parses git/hg progress output (meant
+// to be wired in place of a backend's stderr) and forwards the cumulative
+// byte count it finds to a SharedPullerState.
+type ProgressWriter struct {
+	state *SharedPullerState
+}
+
+// NewProgressWriter returns a ProgressWriter reporting into state.
+func NewProgressWriter(state *SharedPullerState) *ProgressWriter {
+	return &ProgressWriter{state: state}
+}
+
+// Write implements io.Writer. It never fails: a line it cannot parse is
+// simply ignored, since the output is only used for observability.
+func (w *ProgressWriter) Write(p []byte) (int, error) {
+	if m := progressRe.FindSubmatch(p); m != nil {
+		if n, err := strconv.ParseFloat(string(m[1]), 64); err == nil {
+			w.state.SetBytes(int64(n * float64(unitMultiplier[string(m[2])])))
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package puller tracks the live state of every repository currently being
+// fetched, mirroring Syncthing's shared-puller-state pattern, and exposes
+// it through an HTTP /status and Prometheus-style /metrics endpoint (see
+// NewServer). It gives operators the visibility glog lines alone cannot.
+package puller
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase is one step of a repository's clone/update cycle.
+type Phase string
+
+// The phases a repository goes through while being fetched.
+const (
+	PhaseExtracting Phase = "extracting"
+	PhaseCloning    Phase = "cloning"
+	PhaseUpdating   Phase = "updating"
+	PhaseTarring    Phase = "tarring"
+	PhaseCleanup    Phase = "cleanup"
+)
+
+// State is an immutable snapshot of a SharedPullerState, safe to read or
+// serialize without holding any lock.
+type State struct {
+	RepoID    uint64
+	URL       string
+	Phase     Phase
+	StartTime time.Time
+	LastError string
+	Bytes     int64
+	Retries   int
+}
+
+// SharedPullerState tracks the live progress of a single repository's
+// clone/update cycle. It is safe for concurrent use: one goroutine mutates
+// it as the fetch progresses while the HTTP server reads a Snapshot of it
+// at any time.
+type SharedPullerState struct {
+	mu        sync.Mutex
+	repoID    uint64
+	url       string
+	phase     Phase
+	startTime time.Time
+	lastErr   string
+	bytes     int64
+	retries   int
+}
+
+// SetPhase records the step of the clone/update cycle currently running.
+func (s *SharedPullerState) SetPhase(p Phase) {
+	s.mu.Lock()
+	s.phase = p
+	s.mu.Unlock()
+}
+
+// SetBytes records the cumulative number of bytes transferred so far.
+func (s *SharedPullerState) SetBytes(n int64) {
+	s.mu.Lock()
+	s.bytes = n
+	s.mu.Unlock()
+}
+
+// SetError records the last error encountered, if any.
+func (s *SharedPullerState) SetError(err error) {
+	s.mu.Lock()
+	if err != nil {
+		s.lastErr = err.Error()
+	} else {
+		s.lastErr = ""
+	}
+	s.mu.Unlock()
+}
+
+// IncRetries increments the retry count by one.
+func (s *SharedPullerState) IncRetries() {
+	s.mu.Lock()
+	s.retries++
+	s.mu.Unlock()
+}
+
+// Snapshot returns a point in time, lock-free copy of the state.
+func (s *SharedPullerState) Snapshot() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return State{
+		RepoID:    s.repoID,
+		URL:       s.url,
+		Phase:     s.phase,
+		StartTime: s.startTime,
+		LastError: s.lastErr,
+		Bytes:     s.bytes,
+		Retries:   s.retries,
+	}
+}
+
+// Registry holds the SharedPullerState of every repository currently being
+// fetched.
+type Registry struct {
+	mu     sync.Mutex
+	states map[uint64]*SharedPullerState
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{states: make(map[uint64]*SharedPullerState)}
+}
+
+// Track registers repoID as actively being fetched and returns its
+// SharedPullerState. The caller must call Untrack once the fetch is done.
+func (r *Registry) Track(repoID uint64, url string) *SharedPullerState {
+	s := &SharedPullerState{
+		repoID:    repoID,
+		url:       url,
+		phase:     PhaseCloning,
+		startTime: time.Now(),
+	}
+
+	r.mu.Lock()
+	r.states[repoID] = s
+	r.mu.Unlock()
+
+	return s
+}
+
+// Untrack removes repoID from the registry once its fetch has completed.
+func (r *Registry) Untrack(repoID uint64) {
+	r.mu.Lock()
+	delete(r.states, repoID)
+	r.mu.Unlock()
+}
+
+// Snapshot returns a point in time copy of the state of every repository
+// currently being fetched.
+func (r *Registry) Snapshot() []State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make([]State, 0, len(r.states))
+	for _, s := range r.states {
+		states = append(states, s.Snapshot())
+	}
+
+	return states
+}
+
+// Len returns the number of repositories currently being fetched.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.states)
+}
@@ -0,0 +1,104 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package puller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Status is the payload served on /status.
+type Status struct {
+	Repos         []State   `json:"repos"`
+	QueueDepth    int       `json:"queue_depth"`
+	WorkersBusy   int       `json:"workers_busy"`
+	WorkersIdle   int       `json:"workers_idle"`
+	LastFetchedID uint64    `json:"last_fetched_id"`
+	NextFetchTime time.Time `json:"next_fetch_time"`
+}
+
+// StatusProvider supplies the fetcher-wide counters the Registry itself
+// does not know about. Every field is optional; a nil func is reported as
+// the type's zero value.
+type StatusProvider struct {
+	QueueDepth    func() int
+	WorkersBusy   func() int
+	WorkersIdle   func() int
+	LastFetchedID func() uint64
+	NextFetchTime func() time.Time
+	ErrBagSize    func() int
+}
+
+func (p StatusProvider) queueDepth() int {
+	if p.QueueDepth == nil {
+		return 0
+	}
+	return p.QueueDepth()
+}
+
+func (p StatusProvider) workersBusy() int {
+	if p.WorkersBusy == nil {
+		return 0
+	}
+	return p.WorkersBusy()
+}
+
+func (p StatusProvider) workersIdle() int {
+	if p.WorkersIdle == nil {
+		return 0
+	}
+	return p.WorkersIdle()
+}
+
+func (p StatusProvider) lastFetchedID() uint64 {
+	if p.LastFetchedID == nil {
+		return 0
+	}
+	return p.LastFetchedID()
+}
+
+func (p StatusProvider) nextFetchTime() time.Time {
+	if p.NextFetchTime == nil {
+		return time.Time{}
+	}
+	return p.NextFetchTime()
+}
+
+func (p StatusProvider) errBagSize() int {
+	if p.ErrBagSize == nil {
+		return 0
+	}
+	return p.ErrBagSize()
+}
+
+// NewServer returns an HTTP server, not yet started, exposing reg and
+// counters on /status (a JSON dump) and /metrics (Prometheus text format).
+// The caller is responsible for calling ListenAndServe (or Serve) on it and
+// for shutting it down.
+func NewServer(addr string, reg *Registry, counters *Counters, sp StatusProvider) *http.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, req *http.Request) {
+		st := Status{
+			Repos:         reg.Snapshot(),
+			QueueDepth:    sp.queueDepth(),
+			WorkersBusy:   sp.workersBusy(),
+			WorkersIdle:   sp.workersIdle(),
+			LastFetchedID: sp.lastFetchedID(),
+			NextFetchTime: sp.nextFetchTime(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	})
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte(counters.renderMetrics(sp.errBagSize())))
+	})
+
+	return &http.Server{Addr: addr, Handler: mux}
+}
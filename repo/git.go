@@ -0,0 +1,197 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// gitRepo is a Repo backed by a git working copy.
+type gitRepo struct {
+	absPath string
+	url     string
+	opts    CloneOptions
+
+	// progress, when set, receives a copy of git's "--progress" stderr
+	// output for the next Clone, Update or CloneFromCache call.
+	progress io.Writer
+
+	// cancel, when set, aborts the next Clone, Update or CloneFromCache
+	// call's underlying git process as soon as it is closed.
+	cancel <-chan struct{}
+}
+
+func (r *gitRepo) URL() string     { return r.url }
+func (r *gitRepo) AbsPath() string { return r.absPath }
+
+// SetProgressOutput implements ProgressReporter.
+func (r *gitRepo) SetProgressOutput(w io.Writer) { r.progress = w }
+
+// SetCancel implements Canceler.
+func (r *gitRepo) SetCancel(stop <-chan struct{}) { r.cancel = stop }
+
+func (r *gitRepo) Clone() error {
+	return r.clone(nil)
+}
+
+// clone runs "git clone" with extraArgs (e.g. the --shared/--reference pair
+// CloneFromCache needs) plus r.cloneFlags(), then applies the sparse
+// checkout and LFS follow-ups every clone path shares.
+func (r *gitRepo) clone(extraArgs []string) error {
+	args := append([]string{"clone", "--progress"}, extraArgs...)
+	args = append(args, r.cloneFlags()...)
+	args = append(args, "--", r.url, r.absPath)
+
+	if err := r.runVCS(exec.Command("git", args...)); err != nil {
+		return err
+	}
+
+	if len(r.opts.SparsePaths) > 0 {
+		setArgs := append([]string{"-C", r.absPath, "sparse-checkout", "set"}, r.opts.SparsePaths...)
+		if err := r.runVCS(exec.Command("git", setArgs...)); err != nil {
+			return err
+		}
+	}
+
+	if r.opts.LFS {
+		if err := r.runVCS(exec.Command("git", "-C", r.absPath, "lfs", "pull")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cloneFlags translates opts into the git clone arguments that trim it
+// down to only the history the code-mining use case actually needs.
+func (r *gitRepo) cloneFlags() []string {
+	var args []string
+
+	if r.opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if r.opts.Depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", r.opts.Depth))
+	}
+	if r.opts.Submodules {
+		args = append(args, "--recurse-submodules")
+	}
+	if len(r.opts.SparsePaths) > 0 {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+
+	return args
+}
+
+// Deepen turns a shallow clone into a full one, as triggered by
+// config.CloneOptionsConfig.DeepenOnUpdate. It implements repo.Deepener.
+func (r *gitRepo) Deepen() error {
+	out, err := exec.Command("git", "-C", r.absPath, "rev-parse", "--is-shallow-repository").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	if strings.TrimSpace(string(out)) != "true" {
+		// already a full clone, nothing to do.
+		return nil
+	}
+
+	return r.runVCS(exec.Command("git", "-C", r.absPath, "fetch", "--progress", "--unshallow"))
+}
+
+func (r *gitRepo) Update() error {
+	return r.runVCS(exec.Command("git", "-C", r.absPath, "pull", "--progress", "--ff-only"))
+}
+
+// CloneFromCache clones the repository sharing objects with the bare mirror
+// at cacheDir, so that forks of the same upstream don't each pay for their
+// own full copy of the history. It applies the same r.opts (Depth,
+// SingleBranch, SparsePaths, Submodules, LFS) as a direct Clone.
+func (r *gitRepo) CloneFromCache(cacheDir string) error {
+	return r.clone([]string{"--shared", "--reference", cacheDir})
+}
+
+func (r *gitRepo) Cleanup() error {
+	return r.runVCS(exec.Command("git", "-C", r.absPath, "gc", "--auto", "--quiet"))
+}
+
+// runVCS runs a VCS command, classifying well known connectivity failures
+// as ErrNetwork, and tees its stderr to r.progress, if set, as it runs
+// rather than only once it completes. If r.cancel is set and closes before
+// the command finishes, the process is killed so that a graceful shutdown
+// never leaves it running, unlocked, after this process exits.
+func (r *gitRepo) runVCS(cmd *exec.Cmd) error {
+	var buf bytes.Buffer
+	if r.progress != nil {
+		cmd.Stderr = io.MultiWriter(&buf, r.progress)
+	} else {
+		cmd.Stderr = &buf
+	}
+	cmd.Stdout = &buf
+
+	if r.cancel == nil {
+		if err := cmd.Run(); err != nil {
+			if isNetworkError(buf.String()) {
+				return ErrNetwork
+			}
+			return fmt.Errorf("%s: %s", err, strings.TrimSpace(buf.String()))
+		}
+		return nil
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			if isNetworkError(buf.String()) {
+				return ErrNetwork
+			}
+			return fmt.Errorf("%s: %s", err, strings.TrimSpace(buf.String()))
+		}
+		return nil
+	case <-r.cancel:
+		_ = cmd.Process.Kill()
+		<-done
+		return ErrAborted
+	}
+}
+
+// runVCS runs a VCS command without progress streaming, classifying well
+// known connectivity failures as ErrNetwork. Used by backends, such as hg,
+// that don't implement ProgressReporter.
+func runVCS(cmd *exec.Cmd) error {
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if isNetworkError(string(out)) {
+			return ErrNetwork
+		}
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func isNetworkError(output string) bool {
+	for _, s := range []string{
+		"Could not resolve host",
+		"Connection timed out",
+		"Connection refused",
+		"unable to access",
+		"Network is unreachable",
+	} {
+		if strings.Contains(output, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// hgRepo is a Repo backed by a mercurial working copy.
+type hgRepo struct {
+	absPath string
+	url     string
+	opts    CloneOptions
+}
+
+func (r *hgRepo) URL() string     { return r.url }
+func (r *hgRepo) AbsPath() string { return r.absPath }
+
+func (r *hgRepo) Clone() error {
+	args := []string{"clone"}
+	if r.opts.Depth > 0 {
+		// hg has no direct --depth equivalent; -r caps the clone at the
+		// changeset whose revision number is Depth, plus its ancestors.
+		args = append(args, "-r", fmt.Sprintf("%d", r.opts.Depth))
+	}
+	args = append(args, "--", r.url, r.absPath)
+
+	return runVCS(exec.Command("hg", args...))
+}
+
+func (r *hgRepo) Update() error {
+	return runVCS(exec.Command("hg", "--cwd", r.absPath, "pull", "--update"))
+}
+
+func (r *hgRepo) Cleanup() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WorkDir returns the absolute working directory for a repository together
+// with the path of the lockfile that guards it. The lockfile lives next to
+// the working dir (e.g. ".foo.lock" for a working dir named "foo") so that
+// it covers both the expanded tree and any sibling ".tar" archive, mirroring
+// the work dir / lock file split used by cmd/go's lockedfile package.
+//
+// absPath is expected to already be an absolute path; vcs is only used to
+// keep the lock naming convention stable should different backends ever
+// need different lock semantics.
+func WorkDir(vcs, absPath string) (dir, lockfile string, err error) {
+	if !filepath.IsAbs(absPath) {
+		if absPath, err = filepath.Abs(absPath); err != nil {
+			return "", "", err
+		}
+	}
+
+	dir = filepath.Clean(absPath)
+	lockfile = filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".lock")
+
+	return dir, lockfile, nil
+}
+
+// Lock is an exclusive-or-shared file lock guarding a repository's working
+// directory and its ".tar" archive for the duration of any operation that
+// mutates them (clone, update, tar, extract, removal). The zero value is not
+// usable; obtain a Lock via NewLock.
+type Lock struct {
+	path string
+	f    *os.File
+}
+
+// NewLock opens (creating if necessary) the lockfile at path. It does not
+// itself acquire the lock; call Lock, RLock or TryLock for that.
+func NewLock(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0770); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0660)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lock{path: path, f: f}, nil
+}
+
+// Lock acquires an exclusive lock, blocking until it is available. Writers
+// (clone, update, tar, extract, RemoveAll) must hold an exclusive lock for
+// the entire duration of the operation.
+func (l *Lock) Lock() error {
+	return lockFile(l.f, true)
+}
+
+// RLock acquires a shared lock, blocking until it is available. Readers that
+// only inspect a repository's working dir should take a shared lock instead
+// of an exclusive one.
+func (l *Lock) RLock() error {
+	return lockFile(l.f, false)
+}
+
+// TryLock attempts to acquire an exclusive lock without blocking. It returns
+// false, nil if the lock is already held by someone else.
+func (l *Lock) TryLock() (bool, error) {
+	return tryLockFile(l.f)
+}
+
+// Unlock releases the lock. It is safe to call from a deferred statement
+// even if the lock was never successfully acquired.
+func (l *Lock) Unlock() error {
+	return unlockFile(l.f)
+}
+
+// Close releases the lock, if held, and closes the underlying file.
+func (l *Lock) Close() error {
+	_ = l.Unlock()
+	return l.f.Close()
+}
@@ -0,0 +1,74 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkDir(t *testing.T) {
+	tests := []struct {
+		absPath      string
+		wantDir      string
+		wantLockfile string
+	}{
+		{
+			absPath:      "/clones/github.com/foo/bar",
+			wantDir:      "/clones/github.com/foo/bar",
+			wantLockfile: "/clones/github.com/foo/.bar.lock",
+		},
+		{
+			// trailing slash should not change the lockfile name
+			absPath:      "/clones/github.com/foo/bar/",
+			wantDir:      "/clones/github.com/foo/bar",
+			wantLockfile: "/clones/github.com/foo/.bar.lock",
+		},
+	}
+
+	for _, tt := range tests {
+		dir, lockfile, err := WorkDir("git", tt.absPath)
+		if err != nil {
+			t.Fatalf("WorkDir(%q) returned an error: %v", tt.absPath, err)
+		}
+		if dir != tt.wantDir {
+			t.Errorf("WorkDir(%q) dir = %q, want %q", tt.absPath, dir, tt.wantDir)
+		}
+		if lockfile != tt.wantLockfile {
+			t.Errorf("WorkDir(%q) lockfile = %q, want %q", tt.absPath, lockfile, tt.wantLockfile)
+		}
+	}
+}
+
+func TestWorkDirRelative(t *testing.T) {
+	dir, lockfile, err := WorkDir("git", "relative/path")
+	if err != nil {
+		t.Fatalf("WorkDir returned an error: %v", err)
+	}
+
+	if !filepath.IsAbs(dir) {
+		t.Errorf("WorkDir did not make a relative absPath absolute: dir = %q", dir)
+	}
+	if !filepath.IsAbs(lockfile) {
+		t.Errorf("WorkDir did not make a relative absPath's lockfile absolute: lockfile = %q", lockfile)
+	}
+}
+
+func TestWorkDirVCSDoesNotAffectNaming(t *testing.T) {
+	gitDir, gitLock, err := WorkDir("git", "/clones/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hgDir, hgLock, err := WorkDir("hg", "/clones/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gitDir != hgDir || gitLock != hgLock {
+		t.Errorf("WorkDir naming differs by vcs: git = (%q, %q), hg = (%q, %q)",
+			gitDir, gitLock, hgDir, hgLock)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2014-2015 The DevMine authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package repo abstracts the version control operations (clone, update,
+// cleanup) needed to keep a local mirror of a remote repository in sync,
+// regardless of the underlying VCS.
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNetwork is returned by Update when the failure looks like a transient
+// connectivity problem rather than local corruption, so that callers can
+// avoid an expensive re-clone that would fail again for the same reason.
+var ErrNetwork = errors.New("repo: network error")
+
+// ErrAborted is returned by Clone or Update when they were killed mid-flight
+// because a Canceler's cancel channel closed, so that callers can tell a
+// graceful shutdown apart from a real fetch failure and leave the existing
+// working copy alone instead of deleting and re-cloning it.
+var ErrAborted = errors.New("repo: aborted")
+
+// Repo is implemented by every supported version control backend. It
+// exposes the minimal set of operations the fetcher needs to keep a local
+// clone of a remote repository in sync.
+type Repo interface {
+	// URL returns the remote clone URL of the repository.
+	URL() string
+
+	// AbsPath returns the absolute path of the local working copy.
+	AbsPath() string
+
+	// Clone creates a brand new local copy of the repository.
+	Clone() error
+
+	// Update brings an existing local copy up to date with the remote.
+	Update() error
+
+	// Cleanup performs any backend specific housekeeping once a
+	// clone/update cycle has completed.
+	Cleanup() error
+}
+
+// CloneOptions trims a Clone down to only the data the code-mining use case
+// actually needs, instead of always fetching the full history.
+type CloneOptions struct {
+	// Depth, if > 0, performs a shallow clone fetching only the last
+	// Depth commits. The mercurial backend instead clones the changeset
+	// whose revision number is Depth, plus its ancestors.
+	Depth int
+
+	// SingleBranch restricts the clone to the remote's default branch.
+	SingleBranch bool
+
+	// SparsePaths, if non-empty, checks out only these paths using a
+	// cone-mode sparse-checkout.
+	SparsePaths []string
+
+	// Submodules, when true, recurses into submodules while cloning. Off
+	// by default, matching plain "git clone".
+	Submodules bool
+
+	// LFS, when true, pulls Git LFS objects after cloning.
+	LFS bool
+}
+
+// Deepener is implemented by Repo backends that support resuming a shallow
+// clone into a full one. Only the git backend currently supports it.
+type Deepener interface {
+	// Deepen turns a shallow clone into a full one. It is a no-op if the
+	// working copy isn't shallow.
+	Deepen() error
+}
+
+// CacheCloner is implemented by Repo backends that can produce their working
+// copy from a local bare mirror instead of fetching the whole history from
+// the remote. Only the git backend currently supports it.
+type CacheCloner interface {
+	// CloneFromCache clones from cacheDir, a local bare mirror of the same
+	// upstream, sharing its object store instead of duplicating it.
+	CloneFromCache(cacheDir string) error
+}
+
+// Canceler is implemented by Repo backends that can abort an in-flight
+// Clone or Update when asked to. Callers use it so that a graceful shutdown
+// actually kills the underlying VCS subprocess instead of merely releasing
+// its lock out from under it, which would leave the process free to keep
+// mutating AbsPath() with no lock held. Only the git backend currently
+// supports it.
+type Canceler interface {
+	// SetCancel directs Clone/Update to abort the underlying VCS process
+	// as soon as stop is closed. A nil stop disables cancellation.
+	SetCancel(stop <-chan struct{})
+}
+
+// ProgressReporter is implemented by Repo backends that can stream their
+// progress output (e.g. git's "--progress" stderr) to an io.Writer while
+// cloning or updating, so that callers can observe bytes transferred
+// without waiting for the operation to finish.
+type ProgressReporter interface {
+	// SetProgressOutput directs progress output to w for the next Clone
+	// or Update call. A nil w disables progress streaming.
+	SetProgressOutput(w io.Writer)
+}
+
+// New creates a new Repo for the given vcs ("git" or "hg"), rooted at
+// absPath and pointing at cloneURL. opts controls how much of the history
+// Clone actually fetches.
+func New(vcs, absPath, cloneURL string, opts CloneOptions) (Repo, error) {
+	switch vcs {
+	case "git":
+		return &gitRepo{absPath: absPath, url: cloneURL, opts: opts}, nil
+	case "hg":
+		return &hgRepo{absPath: absPath, url: cloneURL, opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("repo: unsupported vcs %q", vcs)
+	}
+}